@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2024 Arm Limited. All rights reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cbuild
+
+import (
+	"errors"
+
+	"github.com/open-cmsis-pack/generator-bridge/internal/common"
+	"github.com/open-cmsis-pack/generator-bridge/internal/utils"
+)
+
+type SubsystemIdxType struct {
+	Project string `yaml:"project"`
+}
+
+type SubsystemType struct {
+	CoreName     string `yaml:"core-name"`
+	Board        string `yaml:"board"`
+	Device       string `yaml:"device"`
+	SubsystemIdx SubsystemIdxType
+}
+
+type ParamsType struct {
+	OutPath   string          `yaml:"output-dir"`
+	Board     string          `yaml:"board"`
+	Device    string          `yaml:"device"`
+	Compiler  string          `yaml:"compiler"`
+	Toolchain string          `yaml:"-"`
+	Subsystem []SubsystemType `yaml:"subsystems"`
+}
+
+type CgenFilesType struct {
+	File string `yaml:"file"`
+}
+
+type CgenGroupsType struct {
+	Group string          `yaml:"group"`
+	Files []CgenFilesType `yaml:"files,omitempty"`
+}
+
+type CgenLayerType struct {
+	ForBoard  string           `yaml:"for-board,omitempty"`
+	ForDevice string           `yaml:"for-device,omitempty"`
+	Define    []string         `yaml:"define,omitempty"`
+	AddPath   []string         `yaml:"add-path,omitempty"`
+	Groups    []CgenGroupsType `yaml:"groups,omitempty"`
+}
+
+type CgenType struct {
+	Layer CgenLayerType `yaml:"layer"`
+}
+
+// Read parses a single cbuild.yml file into parms. outPath is the output
+// directory passed on the command line and is only used as a fallback when
+// the file itself does not specify one.
+func Read(path, outPath string, parms *ParamsType) error {
+	if !utils.FileExists(path) {
+		return errors.New("file not found: " + path)
+	}
+
+	err := common.ReadYml(path, parms)
+	if err != nil {
+		return err
+	}
+
+	if parms.OutPath == "" {
+		parms.OutPath = outPath
+	}
+
+	compiler := parms.Compiler
+	if compiler == "" {
+		// cbuild.yml files predating the Toolchain field never had a
+		// compiler: key; the toolchain used to be hard-coded to AC6's
+		// "MDK-ARM V5", so keep defaulting to that for them.
+		compiler = "AC6"
+	}
+	parms.Toolchain, err = ToolchainFor(compiler)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
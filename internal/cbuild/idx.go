@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2024 Arm Limited. All rights reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cbuild
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/open-cmsis-pack/generator-bridge/internal/common"
+	"github.com/open-cmsis-pack/generator-bridge/internal/utils"
+)
+
+// ContextType describes a single build context (target/build-type/core)
+// referenced by a *.cbuild-idx.yml file.
+type ContextType struct {
+	Context   string `yaml:"context"`
+	CbuildYml string `yaml:"cbuild-yml"`
+}
+
+// IdxType mirrors the `build-idx:` section of a *.cbuild-idx.yml file.
+type IdxType struct {
+	BuildIdx struct {
+		// GenDir anchors where generated output is written, relative to
+		// the cbuild-idx.yml's own directory; each context's output-dir
+		// (ParamsType.OutPath) is then resolved relative to GenDir.
+		GenDir   string        `yaml:"gendir"`
+		Contexts []ContextType `yaml:"contexts"`
+	} `yaml:"build-idx"`
+}
+
+// ReadIdx parses a cbuild-idx.yml file and reads the per-context cbuild.yml
+// referenced by each of its contexts, resolved relative to idxPath's
+// directory. The returned ParamsType slice is in the same order as
+// idx.BuildIdx.Contexts, so callers can match context metadata to params by
+// index.
+func ReadIdx(idxPath, outPath string) (IdxType, []ParamsType, error) {
+	var idx IdxType
+
+	if !utils.FileExists(idxPath) {
+		return idx, nil, errors.New("file not found: " + idxPath)
+	}
+
+	err := common.ReadYml(idxPath, &idx)
+	if err != nil {
+		return idx, nil, err
+	}
+	if len(idx.BuildIdx.Contexts) == 0 {
+		return idx, nil, errors.New("cbuild-idx.yml contains no contexts: " + idxPath)
+	}
+
+	baseDir := filepath.Dir(idxPath)
+	parmsList := make([]ParamsType, 0, len(idx.BuildIdx.Contexts))
+	for _, ctx := range idx.BuildIdx.Contexts {
+		var parms ParamsType
+		cbuildPath := filepath.Join(baseDir, ctx.CbuildYml)
+		if err := Read(cbuildPath, outPath, &parms); err != nil {
+			return idx, nil, err
+		}
+		parmsList = append(parmsList, parms)
+	}
+
+	return idx, parmsList, nil
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright (c) 2024 Arm Limited. All rights reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cbuild
+
+import "errors"
+
+// toolchains maps a cbuild `compiler:` value to the CubeMX toolchain
+// string accepted by "project toolchain" in a CubeMX project script.
+var toolchains = map[string]string{
+	"AC6": "MDK-ARM V5",
+	"GCC": "STM32CubeIDE",
+	"IAR": "EWARM V8",
+}
+
+// toolchainOutDirs maps a CubeMX toolchain string to the subdirectory
+// CubeMX generates sources and headers into under STM32CubeMX/.
+var toolchainOutDirs = map[string]string{
+	"MDK-ARM V5":   "MDK-ARM",
+	"STM32CubeIDE": "STM32CubeIDE",
+	"EWARM V8":     "EWARM",
+}
+
+// ToolchainFor resolves the CubeMX toolchain name for a cbuild compiler id,
+// returning an error if the compiler is not supported.
+func ToolchainFor(compiler string) (string, error) {
+	toolchain, ok := toolchains[compiler]
+	if !ok {
+		return "", errors.New("unsupported compiler for CubeMX toolchain selection: " + compiler)
+	}
+
+	return toolchain, nil
+}
+
+// OutDirFor returns the CubeMX output subdirectory for a toolchain string
+// previously resolved by ToolchainFor.
+func OutDirFor(toolchain string) (string, error) {
+	dir, ok := toolchainOutDirs[toolchain]
+	if !ok {
+		return "", errors.New("unsupported CubeMX toolchain: " + toolchain)
+	}
+
+	return dir, nil
+}
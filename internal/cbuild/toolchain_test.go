@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2024 Arm Limited. All rights reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cbuild
+
+import "testing"
+
+func TestToolchainFor(t *testing.T) {
+	tests := []struct {
+		compiler string
+		want     string
+		wantErr  bool
+	}{
+		{"AC6", "MDK-ARM V5", false},
+		{"GCC", "STM32CubeIDE", false},
+		{"IAR", "EWARM V8", false},
+		{"", "", true},
+		{"XC32", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ToolchainFor(tt.compiler)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ToolchainFor(%q) = %q, want error", tt.compiler, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ToolchainFor(%q) returned unexpected error: %v", tt.compiler, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ToolchainFor(%q) = %q, want %q", tt.compiler, got, tt.want)
+		}
+	}
+}
+
+func TestOutDirFor(t *testing.T) {
+	tests := []struct {
+		toolchain string
+		want      string
+		wantErr   bool
+	}{
+		{"MDK-ARM V5", "MDK-ARM", false},
+		{"STM32CubeIDE", "STM32CubeIDE", false},
+		{"EWARM V8", "EWARM", false},
+		{"", "", true},
+		{"Eclipse", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := OutDirFor(tt.toolchain)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("OutDirFor(%q) = %q, want error", tt.toolchain, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("OutDirFor(%q) returned unexpected error: %v", tt.toolchain, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("OutDirFor(%q) = %q, want %q", tt.toolchain, got, tt.want)
+		}
+	}
+}
@@ -18,6 +18,51 @@ type ParamsType struct {
 	DownloadUrl string
 }
 
+// Mode selects how a Backend.Process drives its underlying configurator
+// tool.
+type Mode int
+
+const (
+	// Interactive opens the configurator's GUI, as before.
+	Interactive Mode = iota
+	// BatchGenerate regenerates output from an existing project file
+	// non-interactively, without opening the GUI.
+	BatchGenerate
+	// DryRun only (re)writes the intermediate files derived from the last
+	// successful generation, without invoking the configurator at all.
+	DryRun
+)
+
+// Backend is implemented by a generator backend (e.g. STM32CubeMX) and made
+// available to Read/Process via Register.
+type Backend interface {
+	Process(cbuildYmlPath, outPath, toolPath string, mode Mode) error
+	// ProcessIdx handles a *.cbuild-idx.yml enumerating several build
+	// contexts, the multi-context counterpart to Process.
+	ProcessIdx(cbuildIdxPath, outPath, toolPath string, mode Mode) error
+}
+
+var backends = make(map[string]Backend)
+
+// Register makes a backend available under id, the same id used for that
+// generator in global.generator.yml. It is meant to be called from a
+// backend package's init(), so that adding a new configurator only requires
+// importing that package, without editing this one.
+func Register(id string, backend Backend) {
+	backends[id] = backend
+}
+
+// Lookup returns the backend registered for id, or an error if none has
+// registered itself under that id.
+func Lookup(id string) (Backend, error) {
+	backend, ok := backends[id]
+	if !ok {
+		return nil, errors.New("no generator backend registered for id: " + id)
+	}
+
+	return backend, nil
+}
+
 type GeneratorType struct {
 	Generator []struct {
 		Id          string `yaml:"id"`
@@ -42,14 +87,48 @@ func Read(name string, params *ParamsType) error {
 		return err
 	}
 	for _, genx := range gen.Generator {
-		if genx.Id == "CubeMX" {
+		if _, ok := backends[genx.Id]; ok {
 			params.Id = genx.Id
 			params.DownloadUrl = genx.DownloadUrl
 			break
 		}
 	}
-	if params.Id != "CubeMX" {
-		return errors.New("generator CubeMX missing in global.generator.yml")
+	if params.Id == "" {
+		return errors.New("no registered generator backend found in global.generator.yml")
 	}
 	return nil
 }
+
+// Process reads generatorYmlPath to find which backend applies, looks it up
+// in the registry populated by Register, and dispatches cbuildYmlPath to it.
+// This is the single entry point callers (e.g. main) should use instead of
+// importing and calling a specific backend package directly.
+func Process(generatorYmlPath, cbuildYmlPath, outPath, toolPath string, mode Mode) error {
+	var params ParamsType
+	if err := Read(generatorYmlPath, &params); err != nil {
+		return err
+	}
+
+	backend, err := Lookup(params.Id)
+	if err != nil {
+		return err
+	}
+
+	return backend.Process(cbuildYmlPath, outPath, toolPath, mode)
+}
+
+// ProcessIdx is the multi-context counterpart to Process: it resolves the
+// backend the same way, then dispatches cbuildIdxPath to its ProcessIdx.
+func ProcessIdx(generatorYmlPath, cbuildIdxPath, outPath, toolPath string, mode Mode) error {
+	var params ParamsType
+	if err := Read(generatorYmlPath, &params); err != nil {
+		return err
+	}
+
+	backend, err := Lookup(params.Id)
+	if err != nil {
+		return err
+	}
+
+	return backend.ProcessIdx(cbuildIdxPath, outPath, toolPath, mode)
+}
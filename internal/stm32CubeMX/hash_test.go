@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) 2024 Arm Limited. All rights reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package stm32cubemx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-cmsis-pack/generator-bridge/internal/cbuild"
+)
+
+func TestHashInputsStableAndContentSensitive(t *testing.T) {
+	dir := t.TempDir()
+	iocFile := filepath.Join(dir, "STM32CubeMX.ioc")
+	if err := os.WriteFile(iocFile, []byte("Mcu.Name=STM32F429ZITx"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	parms := &cbuild.ParamsType{Board: "NUCLEO-F429ZI", Device: "STM32F429ZITx"}
+
+	hash1, err := hashInputs(iocFile, parms)
+	if err != nil {
+		t.Fatalf("hashInputs: %v", err)
+	}
+	hash2, err := hashInputs(iocFile, parms)
+	if err != nil {
+		t.Fatalf("hashInputs: %v", err)
+	}
+	if hash1 != hash2 {
+		t.Errorf("hashInputs(%q) not stable across calls: %q != %q", iocFile, hash1, hash2)
+	}
+
+	if err := os.WriteFile(iocFile, []byte("Mcu.Name=STM32F767ZITx"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	hash3, err := hashInputs(iocFile, parms)
+	if err != nil {
+		t.Fatalf("hashInputs: %v", err)
+	}
+	if hash1 == hash3 {
+		t.Errorf("hashInputs did not change after .ioc content changed")
+	}
+
+	otherParms := &cbuild.ParamsType{Board: "NUCLEO-F429ZI", Device: "STM32F429ZITx", Toolchain: "MDK-ARM V5"}
+	hash4, err := hashInputs(iocFile, otherParms)
+	if err != nil {
+		t.Fatalf("hashInputs: %v", err)
+	}
+	if hash3 == hash4 {
+		t.Errorf("hashInputs did not change after parameters changed")
+	}
+}
+
+func TestHashInputsMissingIocFile(t *testing.T) {
+	dir := t.TempDir()
+	parms := &cbuild.ParamsType{Board: "NUCLEO-F429ZI"}
+
+	hash, err := hashInputs(filepath.Join(dir, "does-not-exist.ioc"), parms)
+	if err != nil {
+		t.Fatalf("hashInputs: %v", err)
+	}
+	if hash == "" {
+		t.Errorf("hashInputs returned an empty hash for a missing .ioc file")
+	}
+}
+
+func TestHashUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	hashPath := filepath.Join(dir, ".mxproject.hash")
+
+	if hashUnchanged(hashPath, "abc") {
+		t.Errorf("hashUnchanged(%q) = true before any hash was stored", hashPath)
+	}
+
+	if err := os.WriteFile(hashPath, []byte("abc"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if !hashUnchanged(hashPath, "abc") {
+		t.Errorf("hashUnchanged(%q, \"abc\") = false, want true", hashPath)
+	}
+	if hashUnchanged(hashPath, "def") {
+		t.Errorf("hashUnchanged(%q, \"def\") = true, want false", hashPath)
+	}
+}
+
+func TestStoreHashRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	iocFile := filepath.Join(dir, "STM32CubeMX.ioc")
+	if err := os.WriteFile(iocFile, []byte("Mcu.Name=STM32F429ZITx"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	hashPath := filepath.Join(dir, ".mxproject.hash")
+	parms := &cbuild.ParamsType{Board: "NUCLEO-F429ZI"}
+
+	if err := storeHash(iocFile, parms, hashPath); err != nil {
+		t.Fatalf("storeHash: %v", err)
+	}
+
+	want, err := hashInputs(iocFile, parms)
+	if err != nil {
+		t.Fatalf("hashInputs: %v", err)
+	}
+	if !hashUnchanged(hashPath, want) {
+		t.Errorf("storeHash(%q) did not persist a hash matching hashInputs", hashPath)
+	}
+}
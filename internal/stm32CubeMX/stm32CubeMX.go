@@ -7,7 +7,11 @@
 package stm32cubemx
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path"
@@ -17,12 +21,30 @@ import (
 
 	"github.com/open-cmsis-pack/generator-bridge/internal/cbuild"
 	"github.com/open-cmsis-pack/generator-bridge/internal/common"
+	"github.com/open-cmsis-pack/generator-bridge/internal/generator"
 	"github.com/open-cmsis-pack/generator-bridge/internal/utils"
 	log "github.com/sirupsen/logrus"
 )
 
-func Process(cbuildYmlPath, outPath, cubeMxPath string) error {
-	var projectFile string
+// BackendId is the generator id this package registers itself under, as
+// found under `generator: id:` in global.generator.yml.
+const BackendId = "CubeMX"
+
+type backend struct{}
+
+func (backend) Process(cbuildYmlPath, outPath, toolPath string, mode generator.Mode) error {
+	return Process(cbuildYmlPath, outPath, toolPath, mode)
+}
+
+func (backend) ProcessIdx(cbuildIdxPath, outPath, toolPath string, mode generator.Mode) error {
+	return ProcessIdx(cbuildIdxPath, outPath, toolPath, mode)
+}
+
+func init() {
+	generator.Register(BackendId, backend{})
+}
+
+func Process(cbuildYmlPath, outPath, cubeMxPath string, mode generator.Mode) error {
 	var parms cbuild.ParamsType
 
 	err := ReadCbuildYmlFile(cbuildYmlPath, outPath, &parms)
@@ -44,66 +66,223 @@ func Process(cbuildYmlPath, outPath, cubeMxPath string) error {
 		return err
 	}
 
+	if err := launchWithMode(workDir, &parms, mode); err != nil {
+		return err
+	}
+
+	mxprojectPath := path.Join(workDir, "STM32CubeMX", ".mxproject")
+	mxproject, err := IniReader(mxprojectPath, false)
+	if err != nil {
+		return err
+	}
+
+	return WriteCgenYml(workDir, mxproject, parms)
+}
+
+// ProcessIdx handles a *.cbuild-idx.yml that enumerates several build
+// contexts (e.g. per-target, per-build-type, per-core). Contexts that share
+// the same STM32CubeMX working directory (e.g. the two cores of a dual-core
+// part) launch CubeMX only once; WriteCgenYml is then called per context so
+// each one gets its own <project>.cgen.yml out of the single resulting
+// .mxproject. mode is forwarded to each shared launch the same way Process
+// uses it, so BatchGenerate/DryRun work for multi-context builds too.
+func ProcessIdx(cbuildIdxPath, outPath, cubeMxPath string, mode generator.Mode) error {
+	idx, parmsList, err := cbuild.ReadIdx(cbuildIdxPath, outPath)
+	if err != nil {
+		return err
+	}
+
+	baseDir := path.Dir(cbuildIdxPath)
+	genDir := baseDir
+	if idx.BuildIdx.GenDir != "" {
+		genDir = path.Join(baseDir, idx.BuildIdx.GenDir)
+	}
+
+	type sharedRun struct {
+		mxproject MxprojectType
+		board     string
+		device    string
+	}
+	shared := make(map[string]sharedRun)
+
+	for i := range parmsList {
+		parms := &parmsList[i]
+		ctx := idx.BuildIdx.Contexts[i]
+
+		workDir := path.Join(genDir, parms.OutPath)
+		workDir = filepath.Clean(workDir)
+		workDir = filepath.ToSlash(workDir)
+
+		if err := os.MkdirAll(workDir, os.ModePerm); err != nil {
+			return err
+		}
+
+		run, ok := shared[workDir]
+		if ok {
+			if run.board != parms.Board || run.device != parms.Device {
+				return fmt.Errorf("context %q: output dir %q is already used by a context for board %q/device %q, got board %q/device %q",
+					ctx.Context, workDir, run.board, run.device, parms.Board, parms.Device)
+			}
+		} else {
+			if err := launchWithMode(workDir, parms, mode); err != nil {
+				return fmt.Errorf("context %q: %w", ctx.Context, err)
+			}
+
+			mxprojectPath := path.Join(workDir, "STM32CubeMX", ".mxproject")
+			mxproject, err := IniReader(mxprojectPath, false)
+			if err != nil {
+				return err
+			}
+			run = sharedRun{mxproject: mxproject, board: parms.Board, device: parms.Device}
+			shared[workDir] = run
+		}
+
+		if err := WriteCgenYml(workDir, run.mxproject, *parms); err != nil {
+			return fmt.Errorf("context %q: %w", ctx.Context, err)
+		}
+	}
+
+	return nil
+}
+
+// launchWithMode drives CubeMX for a single working directory according to
+// mode: Interactive launches the GUI (as Process always did before Mode was
+// added), BatchGenerate regenerates non-interactively and skips the run
+// entirely if the .ioc/parameters hash is unchanged, and DryRun only writes
+// the batch script without invoking CubeMX.
+func launchWithMode(workDir string, parms *cbuild.ParamsType, mode generator.Mode) error {
 	cubeIocPath := path.Join(workDir, "STM32CubeMX", "STM32CubeMX.ioc")
+	mxprojectPath := path.Join(workDir, "STM32CubeMX", ".mxproject")
+	hashPath := mxprojectPath + ".hash"
 
-	if utils.FileExists(cubeIocPath) {
-		err := Launch(cubeIocPath, "")
+	switch mode {
+	case generator.DryRun:
+		_, err := WriteBatchScript(workDir, cubeIocPath)
+		return err
+	case generator.BatchGenerate:
+		hash, err := hashInputs(cubeIocPath, parms)
 		if err != nil {
 			return err
 		}
-	} else {
-		projectFile, err = WriteProjectFile(workDir, &parms)
-		if err != nil {
+		if utils.FileExists(mxprojectPath) && hashUnchanged(hashPath, hash) {
+			log.Infof("STM32CubeMX project unchanged since last run, skipping regeneration")
 			return nil
 		}
-		log.Infof("Generated file: %v", projectFile)
-
-		err := Launch("", projectFile)
-		if err != nil {
+		if err := launchBatch(workDir, cubeIocPath); err != nil {
+			return err
+		}
+		return storeHash(cubeIocPath, parms, hashPath)
+	default:
+		if err := launchForContext(workDir, parms); err != nil {
 			return err
 		}
+		return storeHash(cubeIocPath, parms, hashPath)
 	}
+}
 
-	mxprojectPath := path.Join(workDir, "STM32CubeMX", ".mxproject")
-	mxproject, err := IniReader(mxprojectPath, false)
-	if err != nil {
-		return err
+// launchForContext relaunches CubeMX against an existing STM32CubeMX.ioc if
+// one is already present in workDir, otherwise it writes a fresh project
+// script from parms and launches CubeMX against that.
+func launchForContext(workDir string, parms *cbuild.ParamsType) error {
+	cubeIocPath := path.Join(workDir, "STM32CubeMX", "STM32CubeMX.ioc")
+	if utils.FileExists(cubeIocPath) {
+		return Launch(cubeIocPath, "")
 	}
 
-	err = WriteCgenYml(workDir, mxproject, parms)
+	projectFile, err := WriteProjectFile(workDir, parms)
 	if err != nil {
 		return err
 	}
+	log.Infof("Generated file: %v", projectFile)
 
-	return nil
+	return Launch("", projectFile)
 }
 
+const cubeEnvVar = "STM32CubeMX_PATH"
+const cubeJavaEnvVar = "STM32CubeMX_JAVA"
+
 func Launch(iocFile, projectFile string) error {
 	log.Infof("Launching STM32CubeMX...")
 
-	const cubeEnvVar = "STM32CubeMX_PATH"
+	var args []string
+	if iocFile != "" {
+		args = []string{iocFile}
+	} else if projectFile != "" {
+		args = []string{"-s", projectFile}
+	}
+
+	return runCubeMx(args...)
+}
+
+// runCubeMx resolves java and STM32CubeMX.exe/STM32CubeMX for the current
+// OS and runs STM32CubeMX with extraArgs, returning an error carrying
+// stdout/stderr on failure instead of killing the process via log.Fatal.
+func runCubeMx(extraArgs ...string) error {
 	cubeEnv := os.Getenv(cubeEnvVar)
 	if cubeEnv == "" {
 		return errors.New("environment variable for CubeMX not set: " + cubeEnvVar)
 	}
 
-	pathJava := path.Join(cubeEnv, "jre", "bin", "java.exe")
-	pathCubeMx := path.Join(cubeEnv, "STM32CubeMX.exe")
+	pathJava, err := findJava(cubeEnv)
+	if err != nil {
+		return err
+	}
+	pathCubeMx := findCubeMx(cubeEnv)
 
-	var cmd *exec.Cmd
-	if iocFile != "" {
-		cmd = exec.Command(pathJava, "-jar", pathCubeMx, iocFile)
-	} else if projectFile != "" {
-		cmd = exec.Command(pathJava, "-jar", pathCubeMx, "-s", projectFile)
-	} else {
-		cmd = exec.Command(pathJava, "-jar", pathCubeMx)
+	args := append([]string{"-jar", pathCubeMx}, extraArgs...)
+	cmd := exec.Command(pathJava, args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("STM32CubeMX launch failed: %w\nstdout: %s\nstderr: %s", err, stdout.String(), stderr.String())
+	}
+
+	return nil
+}
+
+// findJava resolves the java binary used to launch STM32CubeMX. The
+// STM32CubeMX_JAVA environment variable always takes precedence; otherwise
+// the bundled JRE under cubeEnv/jre/bin is used if present, falling back to
+// a java found on PATH (e.g. on Linux/macOS installs that don't ship one).
+func findJava(cubeEnv string) (string, error) {
+	if override := os.Getenv(cubeJavaEnvVar); override != "" {
+		return override, nil
+	}
+
+	javaExe := "java"
+	if runtime.GOOS == "windows" {
+		javaExe = "java.exe"
 	}
-	err := cmd.Run()
+
+	pathJava := filepath.Join(cubeEnv, "jre", "bin", javaExe)
+	if utils.FileExists(pathJava) {
+		return pathJava, nil
+	}
+
+	pathJava, err := exec.LookPath("java")
 	if err != nil {
-		log.Fatal(err)
+		return "", errors.New("no bundled CubeMX JRE found and no java on PATH; set " + cubeJavaEnvVar)
 	}
 
-	return nil
+	return pathJava, nil
+}
+
+// findCubeMx resolves the STM32CubeMX executable for the current OS.
+func findCubeMx(cubeEnv string) string {
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(cubeEnv, "STM32CubeMX.exe")
+	case "darwin":
+		appBundle := filepath.Join(cubeEnv, "STM32CubeMX.app", "Contents", "MacOS", "STM32CubeMX")
+		if utils.FileExists(appBundle) {
+			return appBundle
+		}
+		return filepath.Join(cubeEnv, "STM32CubeMX")
+	default:
+		return filepath.Join(cubeEnv, "STM32CubeMX")
+	}
 }
 
 func WriteProjectFile(workDir string, parms *cbuild.ParamsType) (string, error) {
@@ -117,7 +296,7 @@ func WriteProjectFile(workDir string, parms *cbuild.ParamsType) (string, error)
 		text.AddLine("load", parms.Device)
 	}
 	text.AddLine("project name", "STM32CubeMX")
-	text.AddLine("project toolchain", utils.AddQuotes("MDK-ARM V5"))
+	text.AddLine("project toolchain", utils.AddQuotes(parms.Toolchain))
 
 	cubeWorkDir := workDir
 	if runtime.GOOS == "windows" {
@@ -138,6 +317,94 @@ func WriteProjectFile(workDir string, parms *cbuild.ParamsType) (string, error)
 	return filePath, nil
 }
 
+// WriteBatchScript writes a CubeMX script that regenerates code from an
+// existing .ioc file without opening the GUI.
+func WriteBatchScript(workDir, iocFile string) (string, error) {
+	filePath := filepath.Join(workDir, "project.script")
+	log.Infof("Writing CubeMX batch script %v", filePath)
+
+	var text utils.TextBuilder
+	text.AddLine("config load", iocFile)
+	text.AddLine("project generate")
+	text.AddLine("exit")
+
+	if utils.FileExists(filePath) {
+		os.Remove(filePath)
+	}
+
+	err := os.WriteFile(filePath, []byte(text.GetLine()), 0600)
+	if err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+// LaunchBatch invokes STM32CubeMX with -q -s scriptFile to run it
+// non-interactively, without showing the GUI.
+func LaunchBatch(scriptFile string) error {
+	log.Infof("Launching STM32CubeMX in batch mode...")
+
+	return runCubeMx("-q", "-s", scriptFile)
+}
+
+// launchBatch writes the batch script for iocFile and runs CubeMX against
+// it non-interactively.
+func launchBatch(workDir, iocFile string) error {
+	scriptFile, err := WriteBatchScript(workDir, iocFile)
+	if err != nil {
+		return err
+	}
+
+	return LaunchBatch(scriptFile)
+}
+
+// hashInputs returns a stable hash over the .ioc file contents (if any) and
+// the parameters driving generation, used to detect whether a previous
+// regeneration is still up to date.
+func hashInputs(iocFile string, parms *cbuild.ParamsType) (string, error) {
+	h := sha256.New()
+
+	if utils.FileExists(iocFile) {
+		data, err := os.ReadFile(iocFile)
+		if err != nil {
+			return "", err
+		}
+		h.Write(data)
+	}
+
+	fmt.Fprintf(h, "%+v", parms)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// storeHash recomputes the hash from iocFile's current (post-generation)
+// contents and writes it to hashPath, so a later BatchGenerate run compares
+// against what CubeMX actually produced rather than the state before it ran.
+func storeHash(iocFile string, parms *cbuild.ParamsType, hashPath string) error {
+	hash, err := hashInputs(iocFile, parms)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(hashPath, []byte(hash), 0600)
+}
+
+// hashUnchanged reports whether hash matches the value last stored at
+// hashPath.
+func hashUnchanged(hashPath, hash string) bool {
+	if !utils.FileExists(hashPath) {
+		return false
+	}
+
+	stored, err := os.ReadFile(hashPath)
+	if err != nil {
+		return false
+	}
+
+	return string(stored) == hash
+}
+
 func ReadCbuildYmlFile(path, outPath string, parms *cbuild.ParamsType) error {
 	log.Infof("Reading cbuild.yml file: '%v'", path)
 	err := cbuild.Read(path, outPath, parms)
@@ -170,18 +437,24 @@ func WriteCgenYml(outPath string, mxproject MxprojectType, inParms cbuild.Params
 		corename := subsystem.CoreName
 		_, corename, _ = strings.Cut(corename, "-")
 
-		WriteCgenYmlSub(outPath, corename, mxproject, subsystem)
-
+		if err := WriteCgenYmlSub(outPath, corename, mxproject, subsystem, inParms.Toolchain); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func WriteCgenYmlSub(outPath, corename string, mxproject MxprojectType, subsystem *cbuild.SubsystemType) error {
+func WriteCgenYmlSub(outPath, corename string, mxproject MxprojectType, subsystem *cbuild.SubsystemType, toolchain string) error {
 	outName := subsystem.SubsystemIdx.Project + ".cgen.yml"
 	outFile := path.Join(outPath, outName)
 	var cgen cbuild.CgenType
-	relativePathAdd := path.Join("STM32CubeMX", "MDK-ARM")
+
+	toolchainDir, err := cbuild.OutDirFor(toolchain)
+	if err != nil {
+		return err
+	}
+	relativePathAdd := path.Join("STM32CubeMX", toolchainDir)
 
 	cgen.Layer.ForBoard = subsystem.Board
 	cgen.Layer.ForDevice = subsystem.Device